@@ -0,0 +1,132 @@
+package ring
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// itemTracker tracks the number of successes and failures required before a
+// single key in a DoBatch call can be considered done.
+type itemTracker struct {
+	minSuccess  int
+	maxFailures int
+	succeeded   int32
+	failed      int32
+}
+
+// recordSuccess returns true the first time enough successes have been
+// recorded to reach quorum for this key.
+func (i *itemTracker) recordSuccess() bool {
+	return atomic.AddInt32(&i.succeeded, 1) == int32(i.minSuccess)
+}
+
+// recordFailure returns true the first time this key has failed on more
+// ingesters than it can tolerate, meaning it can no longer reach quorum.
+func (i *itemTracker) recordFailure() bool {
+	return atomic.AddInt32(&i.failed, 1) == int32(i.maxFailures)+1
+}
+
+// ingesterBatch is the set of keys (by index into the original batch)
+// destined for a single ingester.
+type ingesterBatch struct {
+	ingester     *IngesterDesc
+	indexes      []int
+	itemTrackers []*itemTracker
+}
+
+// batchTracker tallies per-key results as callbacks complete and signals
+// done once every key has reached quorum, or err as soon as any key can no
+// longer reach quorum.
+type batchTracker struct {
+	rpcsPending int32
+	rpcsFailed  int32
+	done        chan struct{}
+	err         chan error
+}
+
+func (b *batchTracker) record(itemTrackers []*itemTracker, err error) {
+	for _, it := range itemTrackers {
+		if err != nil {
+			if it.recordFailure() && atomic.AddInt32(&b.rpcsFailed, 1) == 1 {
+				b.err <- err
+			}
+			continue
+		}
+		if it.recordSuccess() && atomic.AddInt32(&b.rpcsPending, -1) == 0 {
+			b.done <- struct{}{}
+		}
+	}
+}
+
+// DoBatch groups the given keys by the ingester(s) that own them and calls
+// callback once per ingester with the indexes (into keys) of the keys
+// destined for it. It waits for a quorum of successes per key before
+// returning, and calls cleanup once every callback has returned, which may be
+// after DoBatch itself has returned. This lets callers (e.g. the distributor)
+// issue one RPC per ingester instead of one per key; the Get scratch buffers
+// are allocated once per call and reused for every key.
+func DoBatch(ctx context.Context, op Operation, r ReadRing, keys []uint32, callback func(IngesterDesc, []int) error, cleanup func()) error {
+	if len(keys) == 0 {
+		cleanup()
+		return nil
+	}
+
+	bufDescs, bufHosts, bufLive := getBuffers(r.ReplicationFactor())
+
+	itemTrackers := make([]itemTracker, len(keys))
+	ingesters := make(map[string]ingesterBatch, r.ReplicationFactor()*2)
+
+	for i, key := range keys {
+		replicationSet, err := r.Get(key, op, bufDescs, bufHosts, bufLive)
+		if err != nil {
+			cleanup()
+			return err
+		}
+
+		itemTrackers[i] = itemTracker{
+			minSuccess:  len(replicationSet.Ingesters) - replicationSet.MaxErrors,
+			maxFailures: replicationSet.MaxErrors,
+		}
+
+		for _, desc := range replicationSet.Ingesters {
+			batch, ok := ingesters[desc.Addr]
+			if !ok {
+				batch = ingesterBatch{ingester: desc}
+			}
+			batch.indexes = append(batch.indexes, i)
+			batch.itemTrackers = append(batch.itemTrackers, &itemTrackers[i])
+			ingesters[desc.Addr] = batch
+		}
+	}
+
+	tracker := batchTracker{
+		rpcsPending: int32(len(itemTrackers)),
+		done:        make(chan struct{}, 1),
+		err:         make(chan error, 1),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(ingesters))
+	for _, batch := range ingesters {
+		go func(batch ingesterBatch) {
+			defer wg.Done()
+			err := callback(*batch.ingester, batch.indexes)
+			tracker.record(batch.itemTrackers, err)
+		}(batch)
+	}
+
+	go func() {
+		wg.Wait()
+		cleanup()
+	}()
+
+	select {
+	case err := <-tracker.err:
+		return err
+	case <-tracker.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
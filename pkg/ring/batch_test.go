@@ -0,0 +1,100 @@
+package ring
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// mockReadRing is a minimal ReadRing backed by a fixed set of ingesters, so
+// DoBatch's quorum/early-termination logic can be tested without a real
+// Ring or KV store.
+type mockReadRing struct {
+	rf        int
+	ingesters []*IngesterDesc
+}
+
+func (m *mockReadRing) Get(key uint32, op Operation, bufDescs []*IngesterDesc, bufHosts []string, bufLive []*IngesterDesc) (ReplicationSet, error) {
+	return ReplicationSet{
+		Ingesters: m.ingesters,
+		MaxErrors: len(m.ingesters) - (len(m.ingesters)/2 + 1),
+	}, nil
+}
+
+func (m *mockReadRing) BatchGet(keys []uint32, op Operation) ([]ReplicationSet, error) {
+	return nil, nil
+}
+
+func (m *mockReadRing) GetAll() (ReplicationSet, error) { return ReplicationSet{}, nil }
+
+func (m *mockReadRing) ReplicationFactor() int { return m.rf }
+
+func (m *mockReadRing) Describe(ch chan<- *prometheus.Desc) {}
+func (m *mockReadRing) Collect(ch chan<- prometheus.Metric) {}
+
+func newMockReadRing(rf int) *mockReadRing {
+	ingesters := make([]*IngesterDesc, rf)
+	for i := range ingesters {
+		ingesters[i] = &IngesterDesc{Addr: fmt.Sprintf("ingester-%d", i)}
+	}
+	return &mockReadRing{rf: rf, ingesters: ingesters}
+}
+
+func TestDoBatch_QuorumSuccess(t *testing.T) {
+	r := newMockReadRing(3)
+	keys := []uint32{1, 2, 3}
+
+	cleanedUp := make(chan struct{})
+	err := DoBatch(context.Background(), Write, r, keys, func(ing IngesterDesc, indexes []int) error {
+		// Succeed on 2 of the 3 replicas for every key: that's a quorum.
+		if ing.Addr == "ingester-2" {
+			return fmt.Errorf("simulated failure")
+		}
+		return nil
+	}, func() { close(cleanedUp) })
+
+	if err != nil {
+		t.Fatalf("expected quorum success, got error: %v", err)
+	}
+	// DoBatch may return before every callback has finished; cleanup runs
+	// once they all have.
+	select {
+	case <-cleanedUp:
+	case <-time.After(time.Second):
+		t.Fatal("expected cleanup to be called")
+	}
+}
+
+func TestDoBatch_FailsWhenQuorumUnreachable(t *testing.T) {
+	r := newMockReadRing(3)
+	keys := []uint32{1}
+
+	err := DoBatch(context.Background(), Write, r, keys, func(ing IngesterDesc, indexes []int) error {
+		// Fail every replica: no key can reach quorum.
+		return fmt.Errorf("simulated failure")
+	}, func() {})
+
+	if err == nil {
+		t.Fatal("expected an error when no key can reach quorum")
+	}
+}
+
+func TestDoBatch_NoKeys(t *testing.T) {
+	r := newMockReadRing(3)
+
+	var cleanedUp bool
+	err := DoBatch(context.Background(), Write, r, nil, func(ing IngesterDesc, indexes []int) error {
+		t.Fatal("callback should not be called with no keys")
+		return nil
+	}, func() { cleanedUp = true })
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cleanedUp {
+		t.Fatal("expected cleanup to be called even with no keys")
+	}
+}
@@ -0,0 +1,158 @@
+package ring
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"go.etcd.io/etcd/clientv3"
+
+	"github.com/weaveworks/cortex/pkg/util"
+)
+
+func init() {
+	RegisterKVClient("etcd", func(cfg KVConfig, codec Codec) (KVClient, error) {
+		return NewEtcdClient(cfg.EtcdConfig, codec)
+	})
+}
+
+// EtcdConfig is the config for an etcd-backed KVClient.
+type EtcdConfig struct {
+	Endpoints   string
+	DialTimeout time.Duration
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *EtcdConfig) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&cfg.Endpoints, "etcd.endpoints", "", "Comma-separated list of etcd endpoints to connect to.")
+	f.DurationVar(&cfg.DialTimeout, "etcd.dial-timeout", 10*time.Second, "The dial timeout for the etcd connection.")
+}
+
+// EtcdClient is a KVClient backed by etcd, using the clientv3 Watch/Txn APIs
+// for CAS and watches.
+type EtcdClient struct {
+	cfg    EtcdConfig
+	codec  Codec
+	client *clientv3.Client
+}
+
+// NewEtcdClient makes a new EtcdClient.
+func NewEtcdClient(cfg EtcdConfig, codec Codec) (*EtcdClient, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   strings.Split(cfg.Endpoints, ","),
+		DialTimeout: cfg.DialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &EtcdClient{
+		cfg:    cfg,
+		codec:  codec,
+		client: client,
+	}, nil
+}
+
+// CAS implements KVClient.
+func (c *EtcdClient) CAS(ctx context.Context, key string, f func(in interface{}) (out interface{}, retry bool, err error)) error {
+	for {
+		resp, err := c.client.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		var current interface{}
+		var modRevision int64
+		if len(resp.Kvs) > 0 {
+			current, err = c.codec.Decode(resp.Kvs[0].Value)
+			if err != nil {
+				return err
+			}
+			modRevision = resp.Kvs[0].ModRevision
+		}
+
+		out, retry, err := f(current)
+		if err != nil {
+			return err
+		}
+		if out == nil {
+			return nil
+		}
+
+		buf, err := c.codec.Encode(out)
+		if err != nil {
+			return err
+		}
+
+		txnResp, err := c.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", modRevision)).
+			Then(clientv3.OpPut(key, string(buf))).
+			Commit()
+		if err != nil {
+			return err
+		}
+		if txnResp.Succeeded {
+			return nil
+		}
+		if !retry {
+			return nil
+		}
+	}
+}
+
+// WatchKey implements KVClient.
+func (c *EtcdClient) WatchKey(ctx context.Context, key string, f func(interface{}) bool) {
+	resp, err := c.client.Get(ctx, key)
+	if err != nil {
+		level.Error(util.Logger).Log("msg", "error getting key from etcd", "key", key, "err", err)
+	} else if len(resp.Kvs) > 0 {
+		value, err := c.codec.Decode(resp.Kvs[0].Value)
+		if err != nil {
+			level.Error(util.Logger).Log("msg", "error decoding value from etcd", "key", key, "err", err)
+		} else if !f(value) {
+			return
+		}
+	} else if !f(nil) {
+		return
+	}
+
+	watchChan := c.client.Watch(ctx, key)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case watchResp, ok := <-watchChan:
+			if !ok {
+				return
+			}
+			for _, event := range watchResp.Events {
+				value, err := c.codec.Decode(event.Kv.Value)
+				if err != nil {
+					level.Error(util.Logger).Log("msg", "error decoding value from etcd", "key", key, "err", err)
+					continue
+				}
+				if !f(value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Get implements KVClient.
+func (c *EtcdClient) Get(ctx context.Context, key string) (interface{}, error) {
+	resp, err := c.client.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	return c.codec.Decode(resp.Kvs[0].Value)
+}
+
+// Stop implements KVClient.
+func (c *EtcdClient) Stop() {
+	c.client.Close()
+}
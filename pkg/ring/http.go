@@ -0,0 +1,165 @@
+package ring
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/weaveworks/cortex/pkg/util"
+)
+
+var ringPageTemplate = template.Must(template.New("ring").Parse(`
+<!DOCTYPE html>
+<html>
+	<head><title>Ring Status</title></head>
+	<body>
+		<h1>Ring Status</h1>
+		<form method="POST">
+		<table border="1">
+			<thead>
+				<tr>
+					<th>Instance</th><th>Address</th><th>State</th><th>Zone</th>
+					<th>Tokens</th><th>Ownership</th><th>Last Heartbeat</th><th></th>
+				</tr>
+			</thead>
+			<tbody>
+				{{ range $i := .Ingesters }}
+				<tr>
+					<td>{{ $i.ID }}</td>
+					<td>{{ $i.Addr }}</td>
+					<td>{{ $i.State }}</td>
+					<td>{{ $i.Zone }}</td>
+					<td>{{ $i.NumTokens }}</td>
+					<td>{{ printf "%.2f" $i.Ownership }}%</td>
+					<td>{{ $i.Heartbeat }}</td>
+					<td>
+						{{ if $.ForgetEnabled }}
+						<button name="forget" value="{{ $i.ID }}" type="submit">Forget</button>
+						{{ end }}
+					</td>
+				</tr>
+				{{ end }}
+			</tbody>
+		</table>
+		</form>
+	</body>
+</html>`))
+
+// ingesterStatus is the per-ingester view rendered by the ring status page,
+// in both its HTML and ?format=json forms.
+type ingesterStatus struct {
+	ID        string  `json:"id"`
+	Addr      string  `json:"address"`
+	State     string  `json:"state"`
+	Zone      string  `json:"zone"`
+	NumTokens int     `json:"num_tokens"`
+	Ownership float64 `json:"ownership_percent"`
+	Heartbeat string  `json:"last_heartbeat"`
+}
+
+type ringStatusPage struct {
+	Ingesters     []ingesterStatus `json:"ingesters"`
+	ForgetEnabled bool             `json:"-"`
+}
+
+// ServeHTTP renders the current state of the ring: one row per ingester
+// with its address, state, zone, heartbeat and ownership. Pass
+// ?format=json to get the same data back as JSON instead of HTML.
+//
+// A POST with a "forget" form value removes the named ingester from the
+// ring via a CAS against the KV store; this is only wired up when
+// Config.ForgetEnabled is set, since it lets an operator permanently
+// remove an ingester that may still be trying to rejoin.
+func (r *Ring) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPost {
+		if !r.cfg.ForgetEnabled {
+			http.Error(w, "forget action is disabled", http.StatusForbidden)
+			return
+		}
+
+		id := req.FormValue("forget")
+		if id == "" {
+			http.Error(w, "missing forget parameter", http.StatusBadRequest)
+			return
+		}
+
+		if err := r.forget(req.Context(), id); err != nil {
+			level.Error(util.Logger).Log("msg", "error forgetting ingester", "ingester", id, "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, req, req.URL.Path, http.StatusFound)
+		return
+	}
+
+	page := r.statusPage()
+
+	if req.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := ringPageTemplate.Execute(w, page); err != nil {
+		level.Error(util.Logger).Log("msg", "error rendering ring status page", "err", err)
+	}
+}
+
+func (r *Ring) statusPage() ringStatusPage {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	numTokens, owned := countTokens(r.ringDesc.Tokens)
+
+	ids := make([]string, 0, len(r.ringDesc.Ingesters))
+	for id := range r.ringDesc.Ingesters {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	page := ringStatusPage{
+		Ingesters:     make([]ingesterStatus, 0, len(ids)),
+		ForgetEnabled: r.cfg.ForgetEnabled,
+	}
+	for _, id := range ids {
+		ingester := r.ringDesc.Ingesters[id]
+		page.Ingesters = append(page.Ingesters, ingesterStatus{
+			ID:        id,
+			Addr:      ingester.Addr,
+			State:     ingester.State.String(),
+			Zone:      ingester.Zone,
+			NumTokens: int(numTokens[id]),
+			Ownership: 100 * float64(owned[id]) / float64(math.MaxUint32),
+			Heartbeat: time.Unix(ingester.Timestamp, 0).String(),
+		})
+	}
+	return page
+}
+
+// forget removes id from the ring via a CAS against the KV store.
+func (r *Ring) forget(ctx context.Context, id string) error {
+	return r.KVClient.CAS(ctx, ConsulKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		ringDesc, ok := in.(*Desc)
+		if !ok || ringDesc == nil {
+			return nil, false, nil
+		}
+
+		delete(ringDesc.Ingesters, id)
+		tokens := make([]*TokenDesc, 0, len(ringDesc.Tokens))
+		for _, token := range ringDesc.Tokens {
+			if token.Ingester != id {
+				tokens = append(tokens, token)
+			}
+		}
+		ringDesc.Tokens = tokens
+
+		return ringDesc, true, nil
+	})
+}
@@ -0,0 +1,96 @@
+package ring
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRing_ServeHTTP_JSON(t *testing.T) {
+	r, kv := newTestRing(t)
+	putIngesterState(t, kv, "ingester-1", ACTIVE)
+	time.Sleep(10 * time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodGet, "/ring?format=json", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var page ringStatusPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("failed to decode JSON response: %v", err)
+	}
+	if len(page.Ingesters) != 1 || page.Ingesters[0].ID != "ingester-1" {
+		t.Fatalf("expected one ingester named ingester-1, got %+v", page.Ingesters)
+	}
+}
+
+func TestRing_ServeHTTP_HTML(t *testing.T) {
+	r, kv := newTestRing(t)
+	putIngesterState(t, kv, "ingester-1", ACTIVE)
+	time.Sleep(10 * time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodGet, "/ring", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Fatalf("expected HTML content type, got %q", ct)
+	}
+}
+
+func TestRing_ServeHTTP_ForgetDisabledByDefault(t *testing.T) {
+	r, _ := newTestRing(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/ring", nil)
+	req.Form = map[string][]string{"forget": {"ingester-1"}}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected forget to be rejected when ForgetEnabled is false, got %d", w.Code)
+	}
+}
+
+func TestRing_ServeHTTP_ForgetRemovesIngester(t *testing.T) {
+	kv := NewInMemoryKVClient()
+	r, err := New(Config{
+		Mock:              kv,
+		ReplicationFactor: 1,
+		ForgetEnabled:     true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create ring: %v", err)
+	}
+	t.Cleanup(r.Stop)
+
+	putIngesterState(t, kv, "ingester-1", ACTIVE)
+	time.Sleep(10 * time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodPost, "/ring", nil)
+	req.Form = map[string][]string{"forget": {"ingester-1"}}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected a redirect after forgetting, got %d", w.Code)
+	}
+
+	value, err := kv.Get(context.Background(), ConsulKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	desc := value.(*Desc)
+	if _, ok := desc.Ingesters["ingester-1"]; ok {
+		t.Fatal("expected ingester-1 to be removed from the ring")
+	}
+}
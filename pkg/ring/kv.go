@@ -0,0 +1,86 @@
+package ring
+
+import (
+	"context"
+	"flag"
+	"fmt"
+)
+
+// Codec encodes and decodes values stored in a KVClient.
+type Codec interface {
+	Decode([]byte) (interface{}, error)
+	Encode(interface{}) ([]byte, error)
+}
+
+// KVClient is the interface the ring uses to store and watch its state. It
+// is implemented by consul, etcd, memberlist and an in-memory client used in
+// tests.
+type KVClient interface {
+	// CAS atomically modifies a value in the store. f is called with the
+	// current value, and returns the value to write (or retry=true to have
+	// f called again with a freshly-read current value, e.g. on a CAS
+	// conflict).
+	CAS(ctx context.Context, key string, f func(in interface{}) (out interface{}, retry bool, err error)) error
+
+	// WatchKey calls f whenever the value stored under key changes.
+	// f returning false stops the watch.
+	WatchKey(ctx context.Context, key string, f func(interface{}) bool)
+
+	// Get returns the current value stored under key.
+	Get(ctx context.Context, key string) (interface{}, error)
+
+	// Stop releases any resources held by the client.
+	Stop()
+}
+
+// KVConfig is the config for selecting and configuring a KVClient.
+type KVConfig struct {
+	Store string
+
+	ConsulConfig     ConsulConfig
+	EtcdConfig       EtcdConfig
+	MemberlistConfig MemberlistConfig
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *KVConfig) RegisterFlags(f *flag.FlagSet) {
+	cfg.ConsulConfig.RegisterFlags(f)
+	cfg.EtcdConfig.RegisterFlags(f)
+	cfg.MemberlistConfig.RegisterFlags(f)
+
+	f.StringVar(&cfg.Store, "ring.store", "consul", "Backend storage to use for the ring (consul, etcd, memberlist, inmemory).")
+}
+
+// ringCodec is the Codec used to store and retrieve the ring's Desc.
+var ringCodec = ProtoCodec{Factory: ProtoDescFactory}
+
+// kvClientFactory builds a KVClient for a given KVConfig and codec.
+type kvClientFactory func(cfg KVConfig, codec Codec) (KVClient, error)
+
+// kvClientFactories holds the backends accepted by -ring.store. New
+// backends register themselves here via RegisterKVClient (typically from an
+// init() function in the file implementing the backend) instead of being
+// wired directly into ring.New, so a new backend never has to touch
+// ring.go.
+var kvClientFactories = map[string]kvClientFactory{
+	"consul": func(cfg KVConfig, codec Codec) (KVClient, error) {
+		return NewConsulClient(cfg.ConsulConfig, codec)
+	},
+	"inmemory": func(cfg KVConfig, codec Codec) (KVClient, error) {
+		return NewInMemoryKVClient(), nil
+	},
+}
+
+// RegisterKVClient adds a new backend to the set accepted by -ring.store.
+func RegisterKVClient(name string, factory kvClientFactory) {
+	kvClientFactories[name] = factory
+}
+
+// newKVClient creates a KVClient for the backend named by cfg.Store.
+func newKVClient(cfg KVConfig, codec Codec) (KVClient, error) {
+	factory, ok := kvClientFactories[cfg.Store]
+	if !ok {
+		return nil, fmt.Errorf("invalid KV store type: %s", cfg.Store)
+	}
+	return factory(cfg, codec)
+}
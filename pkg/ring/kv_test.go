@@ -0,0 +1,34 @@
+package ring
+
+import "testing"
+
+func TestNewKVClient_UnknownStoreErrors(t *testing.T) {
+	if _, err := newKVClient(KVConfig{Store: "not-a-real-backend"}, ringCodec); err == nil {
+		t.Fatal("expected an error for an unregistered store name")
+	}
+}
+
+func TestNewKVClient_InMemory(t *testing.T) {
+	client, err := newKVClient(KVConfig{Store: "inmemory"}, ringCodec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client == nil {
+		t.Fatal("expected a non-nil client")
+	}
+}
+
+func TestRegisterKVClient_AddsNewBackend(t *testing.T) {
+	called := false
+	RegisterKVClient("test-backend", func(cfg KVConfig, codec Codec) (KVClient, error) {
+		called = true
+		return NewInMemoryKVClient(), nil
+	})
+
+	if _, err := newKVClient(KVConfig{Store: "test-backend"}, ringCodec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !called {
+		t.Fatal("expected the registered factory to be invoked")
+	}
+}
@@ -0,0 +1,285 @@
+package ring
+
+import (
+	"context"
+	"flag"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/weaveworks/cortex/pkg/util"
+)
+
+// TokenGenerator produces the tokens an ingester registers itself with. It
+// is pluggable so alternative strategies (e.g. spreading tokens evenly
+// across zones) can be swapped in without touching the Lifecycler.
+type TokenGenerator interface {
+	// GenerateTokens returns numTokens tokens, none of which are in taken.
+	GenerateTokens(numTokens int, taken map[uint32]struct{}) []uint32
+}
+
+// randomTokenGenerator is the default TokenGenerator: it picks tokens
+// uniformly at random and retries on collision.
+type randomTokenGenerator struct{}
+
+// GenerateTokens implements TokenGenerator.
+func (randomTokenGenerator) GenerateTokens(numTokens int, taken map[uint32]struct{}) []uint32 {
+	tokens := make([]uint32, 0, numTokens)
+	for len(tokens) < numTokens {
+		candidate := rand.Uint32()
+		if _, ok := taken[candidate]; ok {
+			continue
+		}
+		taken[candidate] = struct{}{}
+		tokens = append(tokens, candidate)
+	}
+	return tokens
+}
+
+// LifecyclerConfig is the config for a Lifecycler.
+type LifecyclerConfig struct {
+	RingConfig Config
+
+	NumTokens       int
+	HeartbeatPeriod time.Duration
+	JoinAfter       time.Duration
+	TokenGenerator  TokenGenerator
+
+	ID   string
+	Addr string
+	Zone string
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *LifecyclerConfig) RegisterFlags(f *flag.FlagSet) {
+	cfg.RingConfig.RegisterFlags(f)
+
+	f.IntVar(&cfg.NumTokens, "ingester.num-tokens", 128, "Number of tokens for each ingester.")
+	f.DurationVar(&cfg.HeartbeatPeriod, "ingester.heartbeat-period", 5*time.Second, "Period with which to heartbeat this ingester's presence in the ring.")
+	f.DurationVar(&cfg.JoinAfter, "ingester.join-after", 0*time.Second, "Period to wait before generating tokens and joining the ring.")
+}
+
+// Lifecycler manages an ingester's presence in the ring: it registers the
+// instance with a set of tokens, heartbeats them, walks the instance
+// through the PENDING -> JOINING -> ACTIVE -> LEAVING state machine, and
+// removes itself from the ring on shutdown. Its tokens are not handed off
+// to another ingester.
+type Lifecycler struct {
+	cfg      LifecyclerConfig
+	KVClient KVClient
+
+	quit context.CancelFunc
+	done chan struct{}
+
+	actorChan chan func()
+
+	stateMtx sync.RWMutex
+	state    IngesterState
+	tokens   []uint32
+}
+
+// NewLifecycler makes and starts a new Lifecycler.
+func NewLifecycler(cfg LifecyclerConfig, kvClient KVClient) (*Lifecycler, error) {
+	if cfg.TokenGenerator == nil {
+		cfg.TokenGenerator = randomTokenGenerator{}
+	}
+
+	l := &Lifecycler{
+		cfg:       cfg,
+		KVClient:  kvClient,
+		done:      make(chan struct{}),
+		actorChan: make(chan func()),
+		state:     PENDING,
+	}
+
+	var ctx context.Context
+	ctx, l.quit = context.WithCancel(context.Background())
+	go l.loop(ctx)
+	return l, nil
+}
+
+// State returns the lifecycler's current state.
+func (l *Lifecycler) State() IngesterState {
+	l.stateMtx.RLock()
+	defer l.stateMtx.RUnlock()
+	return l.state
+}
+
+// ChangeState schedules a state transition, blocking the caller until it has
+// been applied to the ring.
+func (l *Lifecycler) ChangeState(ctx context.Context, state IngesterState) error {
+	errCh := make(chan error, 1)
+	l.actorChan <- func() {
+		errCh <- l.updateState(ctx, state)
+	}
+	return <-errCh
+}
+
+func (l *Lifecycler) setState(state IngesterState) {
+	l.stateMtx.Lock()
+	defer l.stateMtx.Unlock()
+	l.state = state
+}
+
+// loop is the only goroutine allowed to mutate the lifecycler's view of the
+// ring; everything else talks to it via actorChan, the same pattern the
+// ring's own watch loop uses for ringDesc.
+func (l *Lifecycler) loop(ctx context.Context) {
+	defer close(l.done)
+
+	if l.cfg.JoinAfter > 0 {
+		select {
+		case <-time.After(l.cfg.JoinAfter):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if err := l.autoJoin(ctx); err != nil {
+		level.Error(util.Logger).Log("msg", "failed to join the ring", "err", err)
+	}
+
+	heartbeat := time.NewTicker(l.cfg.HeartbeatPeriod)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case f := <-l.actorChan:
+			f()
+		case <-heartbeat.C:
+			if err := l.updateHeartbeat(ctx); err != nil {
+				level.Error(util.Logger).Log("msg", "failed to heartbeat", "ingester", l.cfg.ID, "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// autoJoin registers the instance as JOINING with NumTokens freshly-generated
+// tokens and then moves it to ACTIVE.
+func (l *Lifecycler) autoJoin(ctx context.Context) error {
+	var tokens []uint32
+
+	err := l.KVClient.CAS(ctx, ConsulKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		ringDesc, ok := in.(*Desc)
+		if !ok || ringDesc == nil {
+			ringDesc = &Desc{Ingesters: map[string]*IngesterDesc{}}
+		}
+
+		// Drop any tokens this instance already owns from a previous run
+		// before generating a fresh set, so a restart/rejoin with the same
+		// ID replaces its tokens instead of accumulating more of them on
+		// every restart.
+		ownTokens := ringDesc.Tokens[:0]
+		taken := map[uint32]struct{}{}
+		for _, token := range ringDesc.Tokens {
+			if token.Ingester == l.cfg.ID {
+				continue
+			}
+			ownTokens = append(ownTokens, token)
+			taken[token.Token] = struct{}{}
+		}
+		ringDesc.Tokens = ownTokens
+
+		tokens = l.cfg.TokenGenerator.GenerateTokens(l.cfg.NumTokens, taken)
+
+		ringDesc.Ingesters[l.cfg.ID] = &IngesterDesc{
+			Addr:      l.cfg.Addr,
+			Zone:      l.cfg.Zone,
+			State:     JOINING,
+			Timestamp: time.Now().Unix(),
+		}
+		for _, token := range tokens {
+			ringDesc.Tokens = append(ringDesc.Tokens, &TokenDesc{Token: token, Ingester: l.cfg.ID})
+		}
+
+		return ringDesc, true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	l.tokens = tokens
+	l.setState(JOINING)
+	return l.updateState(ctx, ACTIVE)
+}
+
+// updateState moves the instance to state both locally and in the ring.
+func (l *Lifecycler) updateState(ctx context.Context, state IngesterState) error {
+	err := l.KVClient.CAS(ctx, ConsulKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		ringDesc, ok := in.(*Desc)
+		if !ok || ringDesc == nil {
+			return nil, false, nil
+		}
+		ingester, ok := ringDesc.Ingesters[l.cfg.ID]
+		if !ok {
+			return nil, false, nil
+		}
+		ingester.State = state
+		ingester.Timestamp = time.Now().Unix()
+		return ringDesc, true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	l.setState(state)
+	return nil
+}
+
+// updateHeartbeat bumps this instance's timestamp in the ring so it isn't
+// considered unhealthy.
+func (l *Lifecycler) updateHeartbeat(ctx context.Context) error {
+	return l.KVClient.CAS(ctx, ConsulKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		ringDesc, ok := in.(*Desc)
+		if !ok || ringDesc == nil {
+			return nil, false, nil
+		}
+		ingester, ok := ringDesc.Ingesters[l.cfg.ID]
+		if !ok {
+			return nil, false, nil
+		}
+		ingester.Timestamp = time.Now().Unix()
+		return ringDesc, true, nil
+	})
+}
+
+// Shutdown moves the instance to LEAVING and then removes it from the ring
+// outright, deleting its IngesterDesc and tokens.
+//
+// There is no option to leave tokens behind for another ingester to claim:
+// that would require an RPC between ingesters to transfer their owned
+// series, which this package doesn't have, so there's no live ingester to
+// claim them and nothing to gain from leaving a heartbeat-less LEAVING
+// entry in the ring. Until that transfer path exists, shutdown always
+// removes the instance rather than leaving it to rot in the ring.
+func (l *Lifecycler) Shutdown(ctx context.Context) error {
+	defer func() {
+		l.quit()
+		<-l.done
+	}()
+
+	if err := l.ChangeState(ctx, LEAVING); err != nil {
+		return err
+	}
+
+	return l.KVClient.CAS(ctx, ConsulKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		ringDesc, ok := in.(*Desc)
+		if !ok || ringDesc == nil {
+			return nil, false, nil
+		}
+
+		delete(ringDesc.Ingesters, l.cfg.ID)
+		tokens := make([]*TokenDesc, 0, len(ringDesc.Tokens))
+		for _, token := range ringDesc.Tokens {
+			if token.Ingester != l.cfg.ID {
+				tokens = append(tokens, token)
+			}
+		}
+		ringDesc.Tokens = tokens
+
+		return ringDesc, true, nil
+	})
+}
@@ -0,0 +1,338 @@
+package ring
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/weaveworks/cortex/pkg/util"
+)
+
+func init() {
+	RegisterKVClient("memberlist", func(cfg KVConfig, codec Codec) (KVClient, error) {
+		return NewMemberlistClient(cfg.MemberlistConfig, codec)
+	})
+}
+
+// MemberlistConfig is the config for a memberlist-backed KVClient.
+type MemberlistConfig struct {
+	JoinMembers    string
+	NodeName       string
+	TombstoneGrace time.Duration
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *MemberlistConfig) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&cfg.JoinMembers, "memberlist.join", "", "Comma-separated list of existing memberlist cluster members to join.")
+	f.StringVar(&cfg.NodeName, "memberlist.nodename", "", "Name of the node in the memberlist cluster (defaults to hostname).")
+	f.DurationVar(&cfg.TombstoneGrace, "memberlist.tombstone-grace-period", time.Hour, "How long a deleted ingester is kept as a tombstone before being forgotten, to stop it reappearing via a gossip merge from a lagging peer.")
+}
+
+// MemberlistClient is a KVClient that disseminates the ring Desc to all
+// members of a memberlist gossip cluster instead of relying on a separate
+// consul/etcd cluster. It avoids the operational cost of running a KV store
+// for small clusters.
+//
+// Since gossip delivers updates out of order and from multiple sources
+// concurrently, values aren't just overwritten: each ingester's record
+// (IngesterDesc plus the tokens it owns) is merged against the version
+// already held locally, with the higher Timestamp winning as a whole. This
+// is whole-record last-write-wins keyed off the existing heartbeat
+// Timestamp, not true per-field merge: a zone change on one node racing a
+// heartbeat from another can still lose the zone change if the heartbeat's
+// Timestamp happens to be newer. Deleted ingesters are kept as a tombstone
+// for TombstoneGrace so that a merge from a peer that hasn't yet heard about
+// the deletion doesn't resurrect them; after the grace period the tombstone
+// is dropped too.
+type MemberlistClient struct {
+	cfg   MemberlistConfig
+	codec Codec
+	list  *memberlist.Memberlist
+
+	mtx        sync.Mutex
+	desc       *Desc
+	tombstones map[string]time.Time
+	watchers   []func(interface{}) bool
+}
+
+// NewMemberlistClient makes a new MemberlistClient and joins the cluster.
+func NewMemberlistClient(cfg MemberlistConfig, codec Codec) (*MemberlistClient, error) {
+	c := &MemberlistClient{
+		cfg:        cfg,
+		codec:      codec,
+		desc:       &Desc{Ingesters: map[string]*IngesterDesc{}},
+		tombstones: map[string]time.Time{},
+	}
+
+	mlCfg := memberlist.DefaultLANConfig()
+	if cfg.NodeName != "" {
+		mlCfg.Name = cfg.NodeName
+	}
+	mlCfg.Delegate = c
+
+	list, err := memberlist.Create(mlCfg)
+	if err != nil {
+		return nil, err
+	}
+	c.list = list
+
+	if cfg.JoinMembers != "" {
+		if _, err := list.Join(strings.Split(cfg.JoinMembers, ",")); err != nil {
+			return nil, err
+		}
+	}
+
+	go c.reapTombstones()
+	return c, nil
+}
+
+func (c *MemberlistClient) reapTombstones() {
+	ticker := time.NewTicker(c.cfg.TombstoneGrace / 10)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.mtx.Lock()
+		for id, deletedAt := range c.tombstones {
+			if time.Since(deletedAt) > c.cfg.TombstoneGrace {
+				delete(c.tombstones, id)
+			}
+		}
+		c.mtx.Unlock()
+	}
+}
+
+// cloneDesc deep-copies d so that callers can freely mutate the Ingesters
+// map and Tokens slice of the copy (as every KVClient.CAS callback in this
+// package does) without racing with merge() or the delegate callbacks, all
+// of which read and write c.desc directly under c.mtx.
+func cloneDesc(d *Desc) *Desc {
+	clone := &Desc{Ingesters: make(map[string]*IngesterDesc, len(d.Ingesters))}
+	for id, ingester := range d.Ingesters {
+		ingesterCopy := *ingester
+		clone.Ingesters[id] = &ingesterCopy
+	}
+	clone.Tokens = make([]*TokenDesc, len(d.Tokens))
+	for i, token := range d.Tokens {
+		tokenCopy := *token
+		clone.Tokens[i] = &tokenCopy
+	}
+	return clone
+}
+
+// merge applies other on top of c.desc, keeping for each ingester whichever
+// of the two copies has the higher Timestamp, and honouring tombstones. A
+// winning ingester's tokens come along with it: c.desc.Tokens is rebuilt to
+// drop the old tokens of every ingester whose record changed and replace
+// them with that ingester's tokens from other, so the ring's token list
+// stays in sync with Ingesters instead of being left permanently empty.
+func (c *MemberlistClient) merge(other *Desc) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	changed := false
+	updated := map[string]struct{}{}
+	for id, ingester := range other.Ingesters {
+		if deletedAt, tombstoned := c.tombstones[id]; tombstoned {
+			if ingester.Timestamp <= deletedAt.Unix() {
+				continue
+			}
+			delete(c.tombstones, id)
+		}
+
+		existing, ok := c.desc.Ingesters[id]
+		if !ok || ingester.Timestamp > existing.Timestamp {
+			c.desc.Ingesters[id] = ingester
+			updated[id] = struct{}{}
+			changed = true
+		}
+	}
+
+	if len(updated) > 0 {
+		tokens := make([]*TokenDesc, 0, len(c.desc.Tokens))
+		for _, token := range c.desc.Tokens {
+			if _, ok := updated[token.Ingester]; !ok {
+				tokens = append(tokens, token)
+			}
+		}
+		for _, token := range other.Tokens {
+			if _, ok := updated[token.Ingester]; ok {
+				tokens = append(tokens, token)
+			}
+		}
+		c.desc.Tokens = tokens
+	}
+
+	return changed
+}
+
+// delete removes id from the local Desc and tombstones it so a stale gossip
+// merge can't bring it back for TombstoneGrace.
+func (c *MemberlistClient) delete(id string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.desc.Ingesters, id)
+	c.tombstones[id] = time.Now()
+
+	tokens := make([]*TokenDesc, 0, len(c.desc.Tokens))
+	for _, token := range c.desc.Tokens {
+		if token.Ingester != id {
+			tokens = append(tokens, token)
+		}
+	}
+	c.desc.Tokens = tokens
+}
+
+func (c *MemberlistClient) notify(value interface{}) {
+	c.mtx.Lock()
+	watchers := c.watchers
+	c.mtx.Unlock()
+
+	for _, f := range watchers {
+		if !f(value) {
+			return
+		}
+	}
+}
+
+// CAS implements KVClient. It applies f to a private copy of c.desc (since
+// every caller in this package mutates the Ingesters map and Tokens slice it
+// is given in place) and gossips the result; the eventual state seen by
+// other nodes is resolved by merge, not by this CAS succeeding or failing
+// against a shared revision.
+func (c *MemberlistClient) CAS(ctx context.Context, key string, f func(in interface{}) (out interface{}, retry bool, err error)) error {
+	c.mtx.Lock()
+	current := cloneDesc(c.desc)
+	c.mtx.Unlock()
+
+	out, _, err := f(current)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+
+	desc, ok := out.(*Desc)
+	if !ok {
+		return nil
+	}
+
+	if c.merge(desc) {
+		c.notify(desc)
+	}
+	return nil
+}
+
+// WatchKey implements KVClient. The watcher registered here stays in
+// c.watchers for the life of the client; once f has returned false or ctx
+// is done it no longer passes values on to f, and it always reports true to
+// notify so that the watchers registered after it are still called.
+func (c *MemberlistClient) WatchKey(ctx context.Context, key string, f func(interface{}) bool) {
+	var mtx sync.Mutex
+	stopped := false
+	watcher := func(value interface{}) bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if !stopped && ctx.Err() == nil && !f(value) {
+			stopped = true
+		}
+		return true
+	}
+
+	c.mtx.Lock()
+	c.watchers = append(c.watchers, watcher)
+	current := c.desc
+	c.mtx.Unlock()
+
+	watcher(current)
+	mtx.Lock()
+	stop := stopped
+	mtx.Unlock()
+	if stop {
+		return
+	}
+	<-ctx.Done()
+}
+
+// Get implements KVClient.
+func (c *MemberlistClient) Get(ctx context.Context, key string) (interface{}, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.desc, nil
+}
+
+// Stop implements KVClient.
+func (c *MemberlistClient) Stop() {
+	if err := c.list.Leave(10 * time.Second); err != nil {
+		level.Warn(util.Logger).Log("msg", "error leaving memberlist cluster", "err", err)
+	}
+	c.list.Shutdown()
+}
+
+// NodeMeta implements memberlist.Delegate.
+func (c *MemberlistClient) NodeMeta(limit int) []byte { return nil }
+
+// NotifyMsg implements memberlist.Delegate.
+func (c *MemberlistClient) NotifyMsg(buf []byte) {
+	value, err := c.codec.Decode(buf)
+	if err != nil {
+		level.Error(util.Logger).Log("msg", "error decoding gossiped ring update", "err", err)
+		return
+	}
+	desc, ok := value.(*Desc)
+	if !ok {
+		return
+	}
+	if c.merge(desc) {
+		c.notify(desc)
+	}
+}
+
+// GetBroadcasts implements memberlist.Delegate.
+func (c *MemberlistClient) GetBroadcasts(overhead, limit int) [][]byte {
+	c.mtx.Lock()
+	buf, err := c.codec.Encode(c.desc)
+	c.mtx.Unlock()
+
+	if err != nil {
+		level.Error(util.Logger).Log("msg", "error encoding ring update for gossip", "err", err)
+		return nil
+	}
+	if len(buf) > limit {
+		return nil
+	}
+	return [][]byte{buf}
+}
+
+// LocalState implements memberlist.Delegate.
+func (c *MemberlistClient) LocalState(join bool) []byte {
+	c.mtx.Lock()
+	buf, err := c.codec.Encode(c.desc)
+	c.mtx.Unlock()
+
+	if err != nil {
+		level.Error(util.Logger).Log("msg", "error encoding ring state for gossip push/pull", "err", err)
+		return nil
+	}
+	return buf
+}
+
+// MergeRemoteState implements memberlist.Delegate.
+func (c *MemberlistClient) MergeRemoteState(buf []byte, join bool) {
+	value, err := c.codec.Decode(buf)
+	if err != nil {
+		level.Error(util.Logger).Log("msg", "error decoding remote ring state", "err", err)
+		return
+	}
+	desc, ok := value.(*Desc)
+	if !ok {
+		return
+	}
+	if c.merge(desc) {
+		c.notify(desc)
+	}
+}
@@ -0,0 +1,134 @@
+package ring
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestMemberlistClient() *MemberlistClient {
+	return &MemberlistClient{
+		cfg:        MemberlistConfig{TombstoneGrace: time.Hour},
+		desc:       &Desc{Ingesters: map[string]*IngesterDesc{}},
+		tombstones: map[string]time.Time{},
+	}
+}
+
+func TestMemberlistClient_MergeNewerTimestampWins(t *testing.T) {
+	c := newTestMemberlistClient()
+
+	c.desc.Ingesters["a"] = &IngesterDesc{Zone: "zone-1", Timestamp: 100}
+	c.desc.Tokens = []*TokenDesc{{Token: 1, Ingester: "a"}}
+
+	changed := c.merge(&Desc{
+		Ingesters: map[string]*IngesterDesc{
+			"a": {Zone: "zone-2", Timestamp: 200},
+		},
+		Tokens: []*TokenDesc{{Token: 2, Ingester: "a"}, {Token: 3, Ingester: "a"}},
+	})
+
+	if !changed {
+		t.Fatal("expected merge to report a change")
+	}
+	if c.desc.Ingesters["a"].Zone != "zone-2" {
+		t.Fatalf("expected the newer record to win, got zone %q", c.desc.Ingesters["a"].Zone)
+	}
+	if len(c.desc.Tokens) != 2 {
+		t.Fatalf("expected the winning ingester's tokens to replace the old ones, got %d tokens", len(c.desc.Tokens))
+	}
+}
+
+func TestMemberlistClient_MergeOlderTimestampLoses(t *testing.T) {
+	c := newTestMemberlistClient()
+
+	c.desc.Ingesters["a"] = &IngesterDesc{Zone: "zone-1", Timestamp: 200}
+	c.desc.Tokens = []*TokenDesc{{Token: 1, Ingester: "a"}}
+
+	changed := c.merge(&Desc{
+		Ingesters: map[string]*IngesterDesc{
+			"a": {Zone: "zone-2", Timestamp: 100},
+		},
+		Tokens: []*TokenDesc{{Token: 2, Ingester: "a"}},
+	})
+
+	if changed {
+		t.Fatal("expected merge to report no change when the incoming record is older")
+	}
+	if c.desc.Ingesters["a"].Zone != "zone-1" {
+		t.Fatalf("expected the existing record to survive, got zone %q", c.desc.Ingesters["a"].Zone)
+	}
+	if len(c.desc.Tokens) != 1 || c.desc.Tokens[0].Token != 1 {
+		t.Fatal("expected the existing tokens to be left untouched")
+	}
+}
+
+func TestMemberlistClient_MergeHonoursTombstone(t *testing.T) {
+	c := newTestMemberlistClient()
+	c.tombstones["a"] = time.Now()
+
+	changed := c.merge(&Desc{
+		Ingesters: map[string]*IngesterDesc{
+			"a": {Zone: "zone-1", Timestamp: time.Now().Add(-time.Minute).Unix()},
+		},
+	})
+
+	if changed {
+		t.Fatal("expected a stale record to be rejected by the tombstone")
+	}
+	if _, ok := c.desc.Ingesters["a"]; ok {
+		t.Fatal("expected the tombstoned ingester to stay deleted")
+	}
+}
+
+func TestMemberlistClient_MergeClearsTombstoneOnNewerRecord(t *testing.T) {
+	c := newTestMemberlistClient()
+	c.tombstones["a"] = time.Now().Add(-time.Minute)
+
+	changed := c.merge(&Desc{
+		Ingesters: map[string]*IngesterDesc{
+			"a": {Zone: "zone-1", Timestamp: time.Now().Unix()},
+		},
+	})
+
+	if !changed {
+		t.Fatal("expected a record newer than the tombstone to be accepted")
+	}
+	if _, tombstoned := c.tombstones["a"]; tombstoned {
+		t.Fatal("expected the tombstone to be cleared once a newer record arrives")
+	}
+}
+
+func TestMemberlistClient_DeleteTombstonesAndDropsTokens(t *testing.T) {
+	c := newTestMemberlistClient()
+	c.desc.Ingesters["a"] = &IngesterDesc{Zone: "zone-1", Timestamp: 100}
+	c.desc.Tokens = []*TokenDesc{{Token: 1, Ingester: "a"}, {Token: 2, Ingester: "b"}}
+
+	c.delete("a")
+
+	if _, ok := c.desc.Ingesters["a"]; ok {
+		t.Fatal("expected the ingester to be removed")
+	}
+	if _, tombstoned := c.tombstones["a"]; !tombstoned {
+		t.Fatal("expected the ingester to be tombstoned")
+	}
+	if len(c.desc.Tokens) != 1 || c.desc.Tokens[0].Ingester != "b" {
+		t.Fatal("expected only the deleted ingester's tokens to be dropped")
+	}
+}
+
+func TestCloneDesc_DeepCopiesIngestersAndTokens(t *testing.T) {
+	original := &Desc{
+		Ingesters: map[string]*IngesterDesc{"a": {Zone: "zone-1", Timestamp: 100}},
+		Tokens:    []*TokenDesc{{Token: 1, Ingester: "a"}},
+	}
+
+	clone := cloneDesc(original)
+	clone.Ingesters["a"].Zone = "zone-2"
+	clone.Tokens[0].Token = 2
+
+	if original.Ingesters["a"].Zone != "zone-1" {
+		t.Fatal("expected mutating the clone to leave the original Ingesters entry untouched")
+	}
+	if original.Tokens[0].Token != 1 {
+		t.Fatal("expected mutating the clone to leave the original Tokens entry untouched")
+	}
+}
@@ -0,0 +1,65 @@
+package ring
+
+import "fmt"
+
+// replicationStrategy decides, given the set of ingesters eligible to hold a
+// key and the operation being performed, which of those ingesters should
+// actually be talked to and how many failures we can tolerate among them.
+// bufLive is scratch space for the returned slice, to avoid allocating on
+// the hot path -- pass in the bufLive returned by getBuffers(), reset to a
+// zero length.
+func (r *Ring) replicationStrategy(ingesters []*IngesterDesc, op Operation, distinctZones map[string]struct{}, bufLive []*IngesterDesc) ([]*IngesterDesc, int, error) {
+	// We need a response from a quorum of ingesters, which is n/2 + 1.  In the
+	// case of a node joining/leaving, the quorum size will be n-1/2+1 - i.e.
+	// this is the case of the happy path, not the dangerous failure path.
+	numReplicas := len(ingesters)
+	quorum := numReplicas/2 + 1
+	maxFailure := numReplicas - quorum
+
+	// If zone-awareness is enabled and the replicas span at least as many
+	// zones as the replication factor, the failure budget is raised to the
+	// largest number of replicas held by any one zone, and quorum is
+	// lowered to match, so that a whole zone can be lost.
+	//
+	// Quorum is never lowered below numReplicas/2+1, though: below that a
+	// write quorum and a read quorum can miss each other entirely
+	// (W+R <= N). When that floor applies, the failure budget goes back to
+	// numReplicas - quorum as well, so the MaxErrors returned never lets a
+	// caller accept fewer successes than quorum; losing a zone that holds
+	// more replicas than that budget then fails the quorum check below.
+	// With fewer zones than the replication factor no adjustment is tried.
+	minQuorum := quorum
+	if r.cfg.ZoneAwarenessEnabled && len(distinctZones) >= r.cfg.ReplicationFactor {
+		maxInZone := 0
+		countPerZone := map[string]int{}
+		for _, ingester := range ingesters {
+			countPerZone[ingester.Zone]++
+			if countPerZone[ingester.Zone] > maxInZone {
+				maxInZone = countPerZone[ingester.Zone]
+			}
+		}
+		if maxInZone > maxFailure {
+			maxFailure = maxInZone
+			quorum = numReplicas - maxFailure
+			if quorum < minQuorum {
+				quorum = minQuorum
+				maxFailure = numReplicas - quorum
+			}
+		}
+	}
+
+	liveIngesters := bufLive
+	for _, ingester := range ingesters {
+		if r.IsHealthy(ingester, op) {
+			liveIngesters = append(liveIngesters, ingester)
+		} else {
+			maxFailure--
+		}
+	}
+
+	if maxFailure < 0 || len(liveIngesters) < quorum {
+		return nil, 0, fmt.Errorf("at least %d live ingesters required, could only find %d", quorum, len(liveIngesters))
+	}
+
+	return liveIngesters, maxFailure, nil
+}
@@ -0,0 +1,85 @@
+package ring
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestRingForStrategy(t *testing.T, rf int, zoneAware bool) *Ring {
+	r, err := New(Config{
+		Mock:                 NewInMemoryKVClient(),
+		ReplicationFactor:    rf,
+		ZoneAwarenessEnabled: zoneAware,
+		HeartbeatTimeout:     time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("failed to create ring: %v", err)
+	}
+	t.Cleanup(r.Stop)
+	return r
+}
+
+func healthyIngester(zone string) *IngesterDesc {
+	return &IngesterDesc{Zone: zone, State: ACTIVE, Timestamp: time.Now().Unix()}
+}
+
+func TestReplicationStrategy_QuorumNeverLowerThanSafeFloor(t *testing.T) {
+	// RF=3 across only 2 zones: one zone necessarily holds 2 of the 3
+	// replicas, so the old code lowered quorum to 1 here, which would let a
+	// write quorum (1) and a read quorum (1) both succeed without ever
+	// overlapping on a replica.
+	r := newTestRingForStrategy(t, 3, true)
+
+	ingesters := []*IngesterDesc{
+		healthyIngester("zone-a"),
+		healthyIngester("zone-a"),
+		healthyIngester("zone-b"),
+	}
+	distinctZones := map[string]struct{}{"zone-a": {}, "zone-b": {}}
+
+	bufLive := make([]*IngesterDesc, 0, 3)
+	live, maxFailure, err := r.replicationStrategy(ingesters, Write, distinctZones, bufLive)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(live)-maxFailure < 2 {
+		t.Fatalf("quorum must never drop below numReplicas/2+1 (2 of 3), got %d live ingesters with maxFailure=%d", len(live), maxFailure)
+	}
+}
+
+func TestReplicationStrategy_ToleratesOneZoneLossWhenZonesCoverRF(t *testing.T) {
+	// RF=3 across 3 distinct zones: losing one whole zone should still
+	// leave quorum satisfiable by the remaining two.
+	r := newTestRingForStrategy(t, 3, true)
+
+	ingesters := []*IngesterDesc{
+		healthyIngester("zone-a"),
+		healthyIngester("zone-b"),
+		healthyIngester("zone-c"),
+	}
+	distinctZones := map[string]struct{}{"zone-a": {}, "zone-b": {}, "zone-c": {}}
+
+	bufLive := make([]*IngesterDesc, 0, 3)
+	live, maxFailure, err := r.replicationStrategy(ingesters, Write, distinctZones, bufLive)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(live) != 3 || maxFailure != 1 {
+		t.Fatalf("expected all 3 ingesters live with 1 tolerable failure, got %d live, maxFailure=%d", len(live), maxFailure)
+	}
+}
+
+func TestReplicationStrategy_FailsBelowQuorum(t *testing.T) {
+	r := newTestRingForStrategy(t, 3, false)
+
+	ingesters := []*IngesterDesc{
+		healthyIngester(""),
+		{Zone: "", State: ACTIVE, Timestamp: time.Now().Add(-time.Hour).Unix()}, // stale, unhealthy
+		{Zone: "", State: ACTIVE, Timestamp: time.Now().Add(-time.Hour).Unix()}, // stale, unhealthy
+	}
+
+	bufLive := make([]*IngesterDesc, 0, 3)
+	if _, _, err := r.replicationStrategy(ingesters, Write, map[string]struct{}{}, bufLive); err == nil {
+		t.Fatal("expected an error when fewer than quorum ingesters are healthy")
+	}
+}
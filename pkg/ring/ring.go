@@ -28,7 +28,7 @@ const (
 type ReadRing interface {
 	prometheus.Collector
 
-	Get(key uint32, op Operation) (ReplicationSet, error)
+	Get(key uint32, op Operation, bufDescs []*IngesterDesc, bufHosts []string, bufLive []*IngesterDesc) (ReplicationSet, error)
 	BatchGet(keys []uint32, op Operation) ([]ReplicationSet, error)
 	GetAll() (ReplicationSet, error)
 	ReplicationFactor() int
@@ -51,6 +51,55 @@ const (
 	Reporting // Special value for inquiring about health
 )
 
+func contains(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplicationMode controls how writes are fanned out across the ring.
+type ReplicationMode int
+
+// Values for ReplicationMode.
+const (
+	// ReplicationModeQuorum is the default: Get(key, Write) returns
+	// ReplicationFactor ingesters and a write is durable once a quorum of
+	// them have acknowledged it.
+	ReplicationModeQuorum ReplicationMode = iota
+	// ReplicationModeSingleWriter (aka "RF-1") has Get(key, Write) return
+	// exactly one ingester: the primary for that key's token, or, when the
+	// primary is not a healthy ACTIVE ingester, the first one that is found
+	// walking on around the ring. A write is
+	// durable as soon as it's appended to that ingester's WAL; reads may
+	// still fan out to replicas that have since pulled the WAL segment.
+	ReplicationModeSingleWriter
+)
+
+func (m ReplicationMode) String() string {
+	switch m {
+	case ReplicationModeSingleWriter:
+		return "rf-1"
+	default:
+		return "quorum"
+	}
+}
+
+// Set implements flag.Value.
+func (m *ReplicationMode) Set(s string) error {
+	switch s {
+	case "rf-1":
+		*m = ReplicationModeSingleWriter
+	case "quorum", "":
+		*m = ReplicationModeQuorum
+	default:
+		return fmt.Errorf("invalid replication mode: %s", s)
+	}
+	return nil
+}
+
 type uint32s []uint32
 
 func (x uint32s) Len() int           { return len(x) }
@@ -62,20 +111,24 @@ var ErrEmptyRing = errors.New("empty ring")
 
 // Config for a Ring
 type Config struct {
-	ConsulConfig
-	store             string
-	HeartbeatTimeout  time.Duration
-	ReplicationFactor int
-	Mock              KVClient
+	KVStore              KVConfig
+	HeartbeatTimeout     time.Duration
+	ReplicationFactor    int
+	ZoneAwarenessEnabled bool
+	ForgetEnabled        bool
+	ReplicationMode      ReplicationMode
+	Mock                 KVClient
 }
 
 // RegisterFlags adds the flags required to config this to the given FlagSet
 func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
-	cfg.ConsulConfig.RegisterFlags(f)
+	cfg.KVStore.RegisterFlags(f)
 
-	f.StringVar(&cfg.store, "ring.store", "consul", "Backend storage to use for the ring (consul, inmemory).")
 	f.DurationVar(&cfg.HeartbeatTimeout, "ring.heartbeat-timeout", time.Minute, "The heartbeat timeout after which ingesters are skipped for reads/writes.")
 	f.IntVar(&cfg.ReplicationFactor, "distributor.replication-factor", 3, "The number of ingesters to write to and read from.")
+	f.BoolVar(&cfg.ZoneAwarenessEnabled, "distributor.zone-awareness-enabled", false, "True to enable the zone-awareness and replicate ingested samples across zones.")
+	f.BoolVar(&cfg.ForgetEnabled, "ring.forget-enabled", false, "Enable the forget-ingester action on the ring status page, which removes an ingester from the ring.")
+	f.Var(&cfg.ReplicationMode, "ring.replication-mode", "Replication mode for writes: 'quorum' (default) or 'rf-1' for single-writer.")
 }
 
 // Ring holds the information about the members of the consistent hash ring.
@@ -85,8 +138,9 @@ type Ring struct {
 	done     chan struct{}
 	quit     context.CancelFunc
 
-	mtx      sync.RWMutex
-	ringDesc *Desc
+	mtx           sync.RWMutex
+	ringDesc      *Desc
+	ringUpdatedCh chan struct{}
 
 	ingesterOwnershipDesc *prometheus.Desc
 	numIngestersDesc      *prometheus.Desc
@@ -102,24 +156,18 @@ func New(cfg Config) (*Ring, error) {
 	store := cfg.Mock
 	if store == nil {
 		var err error
-
-		switch cfg.store {
-		case "consul":
-			codec := ProtoCodec{Factory: ProtoDescFactory}
-			store, err = NewConsulClient(cfg.ConsulConfig, codec)
-		case "inmemory":
-			store = NewInMemoryKVClient()
-		}
+		store, err = newKVClient(cfg.KVStore, ringCodec)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	r := &Ring{
-		cfg:      cfg,
-		KVClient: store,
-		done:     make(chan struct{}),
-		ringDesc: &Desc{},
+		cfg:           cfg,
+		KVClient:      store,
+		done:          make(chan struct{}),
+		ringDesc:      &Desc{},
+		ringUpdatedCh: make(chan struct{}),
 		ingesterOwnershipDesc: prometheus.NewDesc(
 			"cortex_ring_ingester_ownership_percent",
 			"The percent ownership of the ring by ingester",
@@ -160,15 +208,22 @@ func (r *Ring) loop(ctx context.Context) {
 		r.mtx.Lock()
 		defer r.mtx.Unlock()
 		r.ringDesc = ringDesc
+		// Wake up anyone waiting on the previous state (e.g.
+		// WaitRingStability, WaitInstanceState) and give future waiters a
+		// fresh channel to block on.
+		close(r.ringUpdatedCh)
+		r.ringUpdatedCh = make(chan struct{})
 		return true
 	})
 }
 
 // Get returns n (or more) ingesters which form the replicas for the given key.
-func (r *Ring) Get(key uint32, op Operation) (ReplicationSet, error) {
+// bufDescs, bufHosts and bufLive are scratch space for use in this method, to
+// avoid allocating on the hot path -- pass in the result of getBuffers().
+func (r *Ring) Get(key uint32, op Operation, bufDescs []*IngesterDesc, bufHosts []string, bufLive []*IngesterDesc) (ReplicationSet, error) {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
-	return r.getInternal(key, op)
+	return r.getInternal(key, op, bufDescs, bufHosts, bufLive)
 }
 
 // BatchGet returns ReplicationFactor (or more) ingesters which form the replicas
@@ -177,9 +232,12 @@ func (r *Ring) BatchGet(keys []uint32, op Operation) ([]ReplicationSet, error) {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
 
+	// Only bufDescs and bufHosts are reused across keys: getInternal returns
+	// bufLive as ReplicationSet.Ingesters, and each result keeps its own.
+	bufDescs, bufHosts, _ := getBuffers(r.cfg.ReplicationFactor)
 	result := make([]ReplicationSet, len(keys), len(keys))
 	for i, key := range keys {
-		rs, err := r.getInternal(key, op)
+		rs, err := r.getInternal(key, op, bufDescs, bufHosts, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -188,31 +246,84 @@ func (r *Ring) BatchGet(keys []uint32, op Operation) ([]ReplicationSet, error) {
 	return result, nil
 }
 
-func (r *Ring) getInternal(key uint32, op Operation) (ReplicationSet, error) {
+// getBuffers allocates the scratch space used by getInternal and
+// replicationStrategy to avoid per-key allocations on the batch write/read
+// path; callers reuse the returned slices across calls to Get.
+func getBuffers(replicationFactor int) ([]*IngesterDesc, []string, []*IngesterDesc) {
+	return make([]*IngesterDesc, 0, replicationFactor*2), make([]string, 0, replicationFactor*2), make([]*IngesterDesc, 0, replicationFactor*2)
+}
+
+func (r *Ring) getInternal(key uint32, op Operation, bufDescs []*IngesterDesc, bufHosts []string, bufLive []*IngesterDesc) (ReplicationSet, error) {
 	if r.ringDesc == nil || len(r.ringDesc.Tokens) == 0 {
 		return ReplicationSet{}, ErrEmptyRing
 	}
 
+	// In single-writer (RF-1) mode, a write goes to exactly one ingester:
+	// the primary for this key's token. Unlike the quorum path there's no
+	// extra replica to fall back on, so we walk forward from the primary's
+	// token, the same way getInternal does below, until we find one that's
+	// actually ACTIVE and healthy; if none is found the write has nowhere
+	// durable to land and must fail rather than be handed to a dead or
+	// not-yet-joined ingester. Reads are unaffected and continue to fan out
+	// to replicas below.
+	if op == Write && r.cfg.ReplicationMode == ReplicationModeSingleWriter {
+		start := r.search(key)
+		for iterations, i := 0, start; iterations < len(r.ringDesc.Tokens); iterations, i = iterations+1, i+1 {
+			i %= len(r.ringDesc.Tokens)
+			ingester := r.ringDesc.Ingesters[r.ringDesc.Tokens[i].Ingester]
+			if ingester.State == ACTIVE && r.IsHealthy(ingester, op) {
+				return ReplicationSet{
+					Ingesters: []*IngesterDesc{ingester},
+					MaxErrors: 0,
+				}, nil
+			}
+		}
+		return ReplicationSet{}, fmt.Errorf("no healthy ACTIVE ingester found for single-writer write")
+	}
+
 	var (
 		n             = r.cfg.ReplicationFactor
-		ingesters     = make([]*IngesterDesc, 0, n)
-		distinctHosts = map[string]struct{}{}
+		ingesters     = bufDescs[:0]
+		distinctHosts = bufHosts[:0]
+		distinctZones = map[string]struct{}{}
 		start         = r.search(key)
 		iterations    = 0
+		// Once we've scanned the whole ring without finding enough distinct
+		// zones to satisfy n, stop requiring a new zone per replica and fall
+		// back to best-effort (this only matters when there are fewer zones
+		// than the replication factor).
+		enforceZones = r.cfg.ZoneAwarenessEnabled
 	)
-	for i := start; len(distinctHosts) < n && iterations < len(r.ringDesc.Tokens); i++ {
+	for i := start; len(distinctHosts) < n && iterations < 2*len(r.ringDesc.Tokens); i++ {
 		iterations++
 		// Wrap i around in the ring.
 		i %= len(r.ringDesc.Tokens)
+		if iterations > len(r.ringDesc.Tokens) {
+			// We've been round once with zone-awareness enforced and still
+			// don't have enough distinct hosts: there are fewer zones than
+			// the replication factor, so stop requiring a fresh zone.
+			enforceZones = false
+		}
 
 		// We want n *distinct* ingesters.
 		token := r.ringDesc.Tokens[i]
-		if _, ok := distinctHosts[token.Ingester]; ok {
+		if contains(distinctHosts, token.Ingester) {
 			continue
 		}
-		distinctHosts[token.Ingester] = struct{}{}
 		ingester := r.ringDesc.Ingesters[token.Ingester]
 
+		// Ignore the ingester if its zone has already been covered by a
+		// previous replica, so that replicas are spread across failure
+		// domains.
+		if _, ok := distinctZones[ingester.Zone]; ok && enforceZones {
+			continue
+		}
+		if r.cfg.ZoneAwarenessEnabled {
+			distinctZones[ingester.Zone] = struct{}{}
+		}
+
+		distinctHosts = append(distinctHosts, token.Ingester)
+
 		// We do not want to Write to Ingesters that are not ACTIVE, but we do want
 		// to write the extra replica somewhere.  So we increase the size of the set
 		// of replicas for the key. This means we have to also increase the
@@ -228,7 +339,7 @@ func (r *Ring) getInternal(key uint32, op Operation) (ReplicationSet, error) {
 		ingesters = append(ingesters, ingester)
 	}
 
-	liveIngesters, maxFailure, err := r.replicationStrategy(ingesters, op)
+	liveIngesters, maxFailure, err := r.replicationStrategy(ingesters, op, distinctZones, bufLive[:0])
 	if err != nil {
 		return ReplicationSet{}, err
 	}
@@ -269,6 +380,11 @@ func (r *Ring) GetAll() (ReplicationSet, error) {
 	}, nil
 }
 
+// ReplicationFactor implements ReadRing.
+func (r *Ring) ReplicationFactor() int {
+	return r.cfg.ReplicationFactor
+}
+
 func (r *Ring) search(key uint32) int {
 	i := sort.Search(len(r.ringDesc.Tokens), func(x int) bool {
 		return r.ringDesc.Tokens[x].Token > key
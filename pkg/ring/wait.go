@@ -0,0 +1,99 @@
+package ring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ringFingerprint is a snapshot of the bits of ringDesc that rollout
+// automation cares about: which tokens exist and what state each ingester
+// is in. Two fingerprints compare equal iff the ring looks the same from
+// that point of view, regardless of map/slice ordering.
+type ringFingerprint string
+
+func fingerprint(desc *Desc) ringFingerprint {
+	tokens := make([]string, 0, len(desc.Tokens))
+	for _, token := range desc.Tokens {
+		tokens = append(tokens, fmt.Sprintf("%d/%s", token.Token, token.Ingester))
+	}
+	sort.Strings(tokens)
+
+	states := make([]string, 0, len(desc.Ingesters))
+	for id, ingester := range desc.Ingesters {
+		states = append(states, fmt.Sprintf("%s=%s", id, ingester.State))
+	}
+	sort.Strings(states)
+
+	return ringFingerprint(fmt.Sprintf("%v|%v", tokens, states))
+}
+
+// snapshot returns the current ringDesc along with the channel that will be
+// closed the next time it changes.
+func (r *Ring) snapshot() (*Desc, chan struct{}) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.ringDesc, r.ringUpdatedCh
+}
+
+// WaitRingStability blocks until the ring has gone minDuration without any
+// token or ingester-state change, giving up after maxDuration. This is used
+// by rollout automation (scale-up/down, rolling restarts) to know when it's
+// safe to proceed to the next instance, and by tests that would otherwise
+// have to poll Prometheus metrics to find out the ring has settled.
+func (r *Ring) WaitRingStability(ctx context.Context, minDuration, maxDuration time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, maxDuration)
+	defer cancel()
+
+	desc, updated := r.snapshot()
+	last := fingerprint(desc)
+	stableDeadline := time.Now().Add(minDuration)
+
+	for {
+		remaining := time.Until(stableDeadline)
+		if remaining <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+			// No change for minDuration: the ring is stable.
+			return nil
+		case <-updated:
+			timer.Stop()
+			desc, updated = r.snapshot()
+			current := fingerprint(desc)
+			if current == last {
+				// The watch fired but nothing we care about changed (e.g. a
+				// heartbeat-only update); keep counting down towards
+				// stableDeadline rather than resetting it.
+				continue
+			}
+			last = current
+			stableDeadline = time.Now().Add(minDuration)
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// WaitInstanceState blocks until the ingester identified by instanceID
+// reaches the desired state, or ctx is cancelled.
+func (r *Ring) WaitInstanceState(ctx context.Context, instanceID string, desired IngesterState) error {
+	for {
+		desc, updated := r.snapshot()
+
+		if ingester, ok := desc.Ingesters[instanceID]; ok && ingester.State == desired {
+			return nil
+		}
+
+		select {
+		case <-updated:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
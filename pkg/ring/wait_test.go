@@ -0,0 +1,104 @@
+package ring
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestRing(t *testing.T) (*Ring, KVClient) {
+	kv := NewInMemoryKVClient()
+	r, err := New(Config{
+		Mock:              kv,
+		ReplicationFactor: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to create ring: %v", err)
+	}
+	t.Cleanup(r.Stop)
+	return r, kv
+}
+
+func putIngesterState(t *testing.T, kv KVClient, id string, state IngesterState) {
+	err := kv.CAS(context.Background(), ConsulKey, func(in interface{}) (interface{}, bool, error) {
+		desc, ok := in.(*Desc)
+		if !ok || desc == nil {
+			desc = &Desc{Ingesters: map[string]*IngesterDesc{}}
+		}
+		desc.Ingesters[id] = &IngesterDesc{State: state, Timestamp: time.Now().Unix()}
+		return desc, true, nil
+	})
+	if err != nil {
+		t.Fatalf("CAS failed: %v", err)
+	}
+}
+
+func TestWaitInstanceState_ReachesDesiredState(t *testing.T) {
+	r, kv := newTestRing(t)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.WaitInstanceState(context.Background(), "ingester-1", ACTIVE)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	putIngesterState(t, kv, "ingester-1", JOINING)
+	putIngesterState(t, kv, "ingester-1", ACTIVE)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for WaitInstanceState to return")
+	}
+}
+
+func TestWaitInstanceState_ContextCancelled(t *testing.T) {
+	r, _ := newTestRing(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := r.WaitInstanceState(ctx, "missing-ingester", ACTIVE); err == nil {
+		t.Fatal("expected an error once the context deadline passes")
+	}
+}
+
+func TestWaitRingStability_BecomesStable(t *testing.T) {
+	r, kv := newTestRing(t)
+	putIngesterState(t, kv, "ingester-1", ACTIVE)
+
+	// Give the watch loop time to pick up the update before timing
+	// stability, then expect the ring to settle quickly since nothing else
+	// changes afterwards.
+	time.Sleep(10 * time.Millisecond)
+	if err := r.WaitRingStability(context.Background(), 20*time.Millisecond, time.Second); err != nil {
+		t.Fatalf("expected ring to become stable, got: %v", err)
+	}
+}
+
+func TestWaitRingStability_TimesOutOnConstantChurn(t *testing.T) {
+	r, kv := newTestRing(t)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		i := 0
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			putIngesterState(t, kv, "ingester-1", IngesterState(i%2))
+			i++
+			time.Sleep(2 * time.Millisecond)
+		}
+	}()
+
+	if err := r.WaitRingStability(context.Background(), 50*time.Millisecond, 100*time.Millisecond); err == nil {
+		t.Fatal("expected WaitRingStability to give up under constant churn")
+	}
+}